@@ -1,286 +1,367 @@
 package main
 
 import (
-	"encoding/json"
 	"flag"
 	"fmt"
-	"io/ioutil"
 	"log"
-	"math"
-	"math/rand"
+	"net"
 	"net/http"
 	"net/http/httputil"
 	"net/url"
 	"strconv"
 	"strings"
-	"sync"
+	"sync/atomic"
 	"time"
-)
-
-type Location struct {
-	Latitude  interface{} `json:"lat"`
-	Longitude interface{} `json:"lon"`
-}
 
-type Backend struct {
-	URL          *url.URL
-	IP           string
-	Alive        bool
-	ReverseProxy *httputil.ReverseProxy
-	mu           sync.RWMutex
-}
-
-type ServerPool struct {
-	backends []*Backend
-}
+	"github.com/NghiaaPD/Load-Balancer-ResFes/circuitbreaker"
+	"github.com/NghiaaPD/Load-Balancer-ResFes/geoip"
+	"github.com/NghiaaPD/Load-Balancer-ResFes/health"
+	"github.com/NghiaaPD/Load-Balancer-ResFes/metrics"
+	"github.com/NghiaaPD/Load-Balancer-ResFes/pool"
+	"github.com/NghiaaPD/Load-Balancer-ResFes/ratelimit"
+	"github.com/NghiaaPD/Load-Balancer-ResFes/vivaldi"
+)
 
-func (s *ServerPool) AddBackend(backend *Backend) {
-	s.backends = append(s.backends, backend)
-}
+func main() {
+	var serverList string
+	var port int
+	var adminPort int
+	var geoDBPath string
+	var policyName string
+	var metricsBackend string
+	var vivaldiCoordsFile string
+	var connectionRateLimit float64
+	var errorThreshold float64
+	var errorWindow time.Duration
+	var breakerCooldown time.Duration
+	var healthProtocol string
+	var healthPath string
+	var healthMethod string
+	var healthExpectStatus int
+	var healthExpectBody string
+	var healthInterval time.Duration
+	var healthTimeout time.Duration
+	var healthUnhealthyThreshold int
+	var healthHealthyThreshold int
+	var healthPassive bool
+	flag.StringVar(&serverList, "backends", "", "Load balanced backends, use commas to separate. Each entry may carry a weight as \"url|weight\" (used by -policy weighted)")
+	flag.IntVar(&port, "port", 3000, "Port to serve")
+	flag.IntVar(&adminPort, "admin-port", 3001, "Port to serve the /metrics endpoint on")
+	flag.StringVar(&geoDBPath, "geoip-db", "", "Path to a MaxMind GeoLite2/GeoIP2 City .mmdb file for geo-nearest routing")
+	flag.StringVar(&policyName, "policy", "round-robin", "Backend selection policy: round-robin, random, least-connections, weighted, ip-hash, geo-nearest, lowest-latency")
+	flag.StringVar(&metricsBackend, "metrics-backend", "discard", "Metrics sink: discard, expvar, or prometheus")
+	flag.StringVar(&vivaldiCoordsFile, "vivaldi-coords-file", "", "Path to persist the learned Vivaldi coordinate for -policy lowest-latency (skipped if empty)")
+	flag.Float64Var(&connectionRateLimit, "connection-rate-limit", 0, "Max new connections per second per client IP (0 disables rate limiting)")
+	flag.Float64Var(&errorThreshold, "error-threshold", 0.5, "Rolling error rate (0-1) that trips a backend's circuit breaker")
+	flag.DurationVar(&errorWindow, "error-window", 30*time.Second, "How far back the circuit breaker looks when computing a backend's error rate")
+	flag.DurationVar(&breakerCooldown, "breaker-cooldown", 30*time.Second, "How long a tripped circuit breaker stays open before admitting a half-open probe")
+	flag.StringVar(&healthProtocol, "health-protocol", "http", "Active health-check probe: http or tcp")
+	flag.StringVar(&healthPath, "health-path", "", "Path requested by the http health-check probe")
+	flag.StringVar(&healthMethod, "health-method", "GET", "HTTP method used by the http health-check probe")
+	flag.IntVar(&healthExpectStatus, "health-expect-status", 200, "Status code the http health-check probe requires (0 accepts any status)")
+	flag.StringVar(&healthExpectBody, "health-expect-body", "", "Substring the http health-check probe requires in the response body (skipped if empty)")
+	flag.DurationVar(&healthInterval, "health-interval", 5*time.Second, "How often each backend is actively health-checked")
+	flag.DurationVar(&healthTimeout, "health-timeout", 2*time.Second, "Timeout for a single health-check probe")
+	flag.IntVar(&healthUnhealthyThreshold, "health-unhealthy-threshold", 3, "Consecutive failing probes required before a healthy backend is marked unhealthy")
+	flag.IntVar(&healthHealthyThreshold, "health-healthy-threshold", 2, "Consecutive passing probes required before an unhealthy backend is marked healthy again")
+	flag.BoolVar(&healthPassive, "health-passive", false, "Also fold the reverse proxy's observed response status into each backend's health state, without waiting for the next active probe")
+	flag.Parse()
 
-func (s *ServerPool) HealthCheck() {
-	for _, backend := range s.backends {
-		alive := healthCheck(backend.URL.String())
-		backend.mu.Lock()
-		backend.Alive = alive == "Good" || alive == "Average" // Consider "Good" and "Average" as alive
-		backend.mu.Unlock()
+	if len(serverList) == 0 {
+		log.Fatal("Please provide one or more backends to load balance")
 	}
-}
 
-func (s *ServerPool) GetNextBackend() *Backend {
-	var healthyBackends []*Backend
-	for _, backend := range s.backends {
-		backend.mu.RLock()
-		if backend.Alive {
-			healthyBackends = append(healthyBackends, backend)
+	var geoDB *geoip.DB
+	if geoDBPath != "" {
+		var err error
+		geoDB, err = geoip.Open(geoDBPath)
+		if err != nil {
+			log.Fatalf("Failed to open GeoIP database %s: %v", geoDBPath, err)
 		}
-		backend.mu.RUnlock()
 	}
 
-	if len(healthyBackends) == 0 {
-		return nil
-	}
-
-	next := rand.Intn(len(healthyBackends))
-	return healthyBackends[next]
-}
-
-func healthCheck(url string) string {
-	client := http.Client{
-		Timeout: 2 * time.Second,
+	policy, err := newPolicy(policyName, geoDB, vivaldiCoordsFile)
+	if err != nil {
+		log.Fatal(err)
 	}
 
-	start := time.Now()
-	resp, err := client.Get(url)
+	recorder, metricsHandler, err := (metrics.MetricsBuilder{Backend: metricsBackend}).Build()
 	if err != nil {
-		return "Bad"
-	}
-	defer resp.Body.Close()
-
-	elapsed := time.Since(start)
-	if elapsed.Seconds() < 0.5 {
-		return "Good"
-	} else if elapsed.Seconds() < 1 {
-		return "Average"
-	} else {
-		return "Overloaded"
+		log.Fatal(err)
 	}
-}
 
-func haversine(lat1, lon1, lat2, lon2 float64) float64 {
-	// Earth radius in kilometers
-	const earthRadius = 6371
+	lbLat, lbLon, haveLBCoords := lbCoordinates(geoDB)
 
-	// Convert latitude and longitude from degrees to radians
-	lat1Rad := lat1 * (math.Pi / 180)
-	lon1Rad := lon1 * (math.Pi / 180)
-	lat2Rad := lat2 * (math.Pi / 180)
-	lon2Rad := lon2 * (math.Pi / 180)
-
-	// Calculate differences
-	deltaLat := lat2Rad - lat1Rad
-	deltaLon := lon2Rad - lon1Rad
+	var limiter *ratelimit.Limiter
+	if connectionRateLimit > 0 {
+		limiter = ratelimit.New(connectionRateLimit)
+	}
 
-	// Haversine formula
-	a := math.Pow(math.Sin(deltaLat/2), 2) + math.Cos(lat1Rad)*math.Cos(lat2Rad)*math.Pow(math.Sin(deltaLon/2), 2)
-	c := 2 * math.Atan2(math.Sqrt(a), math.Sqrt(1-a))
+	serverPool := pool.New(policy)
 
-	// Distance calculation
-	distance := earthRadius * c
-	return distance
-}
+	fmt.Println("Load balancing across the following backends:")
+	for _, entry := range strings.Split(serverList, ",") {
+		rawURL, weight := parseBackendEntry(entry)
 
-func main() {
-	var serverList string
-	var port int
-	flag.StringVar(&serverList, "backends", "", "Load balanced backends, use commas to separate")
-	flag.IntVar(&port, "port", 3000, "Port to serve")
-	flag.Parse()
+		serverURL, err := url.Parse(rawURL)
+		if err != nil {
+			log.Fatal(err)
+		}
 
-	if len(serverList) == 0 {
-		log.Fatal("Please provide one or more backends to load balance")
-	}
+		backend := &pool.Backend{
+			URL:          serverURL,
+			IP:           serverURL.Hostname(),
+			Weight:       weight,
+			ReverseProxy: httputil.NewSingleHostReverseProxy(serverURL),
+			Breaker:      circuitbreaker.New(errorThreshold, errorWindow, breakerCooldown),
+		}
 
-	servers := strings.Split(serverList, ",")
-	fmt.Println("Load balancing across the following backends:")
-	//for _, server := range servers {
-	//	parsedURL, err := url.Parse(server)
-	//	if err != nil {
-	//		log.Fatalf("Failed to parse URL %s: %v", server, err)
-	//	}
-	//	ipOfServers := parsedURL.Hostname()
-	//	coordinatesOfServer, err := getCoordinatesFromIP(ipOfServers)
-	//	fmt.Println(coordinatesOfServer)
-	//}
-
-	serverPool := &ServerPool{}
-
-	for _, server := range servers {
-		serverURL, err := url.Parse(server)
+		prober, err := newProber(healthProtocol, serverURL, healthMethod, healthPath, healthExpectStatus, healthExpectBody, healthTimeout)
 		if err != nil {
 			log.Fatal(err)
 		}
+		backend.Health = health.NewChecker(prober, healthUnhealthyThreshold, healthHealthyThreshold)
 
-		ip := serverURL.Hostname()
-		proxy := httputil.NewSingleHostReverseProxy(serverURL)
-		alive := healthCheck(server)
-		backend := &Backend{
-			URL:          serverURL,
-			IP:           ip,
-			Alive:        alive == "Good" || alive == "Average", // Consider "Good" and "Average" as alive
-			ReverseProxy: proxy,
+		wireProxyHooks(backend, healthPassive, recorder)
+
+		if geoDB != nil {
+			if resolvedIPs, err := net.LookupIP(backend.IP); err == nil && len(resolvedIPs) > 0 {
+				if lat, lon, ok := geoDB.Lookup(resolvedIPs[0]); ok {
+					backend.Latitude = lat
+					backend.Longitude = lon
+					backend.HasCoords = true
+				}
+			}
+		}
+
+		if backend.HasCoords {
+			backend.Coord = vivaldi.SeedFromLatLon(backend.Latitude, backend.Longitude)
+		} else {
+			backend.Coord = vivaldi.SeedFromString(backend.URL.String())
 		}
+
 		serverPool.AddBackend(backend)
+		fmt.Printf("  %s (weight %d)\n", backend.URL, backend.Weight)
 	}
 
+	rttObserver, _ := policy.(pool.RTTObserver)
+
+	runHealthChecks := func() {
+		serverPool.HealthCheck(func(backend *pool.Backend, result health.Result, transitioned bool) {
+			label := backend.URL.String()
+			status := healthStatusLabel(backend.IsAlive())
+			recorder.IncHealthCheck(label, result.Healthy)
+			recorder.SetHealthStatus(label, status)
+			recorder.SetActiveConnections(label, atomic.LoadInt64(&backend.ActiveConnections))
+			if haveLBCoords && backend.HasCoords {
+				recorder.SetDistance(label, geoip.Haversine(lbLat, lbLon, backend.Latitude, backend.Longitude))
+			}
+			if transitioned {
+				log.Printf("health: backend %s transitioned to %s (mode=active, rtt=%s, err=%v)\n", label, status, result.RTT, result.Err)
+			}
+			if rttObserver != nil && backend.IsAlive() {
+				rttObserver.RecordRTT(backend, result.RTT)
+			}
+		})
+	}
+
+	runHealthChecks()
 	go func() {
 		for {
-			serverPool.HealthCheck()
-			time.Sleep(5 * time.Second)
+			time.Sleep(healthInterval)
+			runHealthChecks()
+		}
+	}()
+
+	go func() {
+		adminMux := http.NewServeMux()
+		adminMux.Handle("/metrics", metricsHandler)
+		log.Printf("Metrics endpoint listening at :%d/metrics (%s)\n", adminPort, metricsBackend)
+		if err := http.ListenAndServe(fmt.Sprintf(":%d", adminPort), adminMux); err != nil {
+			log.Fatal(err)
 		}
 	}()
 
 	server := http.Server{
 		Addr: fmt.Sprintf(":%d", port),
 		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			peer := serverPool.GetNextBackend()
+			if limiter != nil && !limiter.Allow(remoteHost(r)) {
+				http.Error(w, "Too many connections", http.StatusTooManyRequests)
+				return
+			}
+
+			peer := serverPool.Select(r)
 
-			if peer != nil {
-				peer.ReverseProxy.ServeHTTP(w, r)
+			if peer == nil {
+				http.Error(w, "Service not available", http.StatusServiceUnavailable)
 				return
 			}
 
-			http.Error(w, "Service not available", http.StatusServiceUnavailable)
+			label := peer.URL.String()
+			start := time.Now()
+			peer.ServeHTTP(w, r)
+			recorder.IncRequest(label)
+			recorder.ObserveLatency(label, time.Since(start))
+			recorder.SetActiveConnections(label, atomic.LoadInt64(&peer.ActiveConnections))
 		}),
 	}
 
-	ipOfLB := getPublicIP()
-	fmt.Println("\x1b[31mINFORMATION OF LOAD BALANCER\x1b[0m")
-	fmt.Println("Your public IP address is:", ipOfLB)
-	coordinatesOfLB, err := getCoordinatesFromIP(ipOfLB)
-	if err != nil {
-		log.Fatal("Failed to get coordinates:", err)
+	log.Printf("Load Balancer started at :%d using %q policy\n", port, policyName)
+	if err := server.ListenAndServe(); err != nil {
+		log.Fatal(err)
 	}
+}
 
-	fmt.Printf("Coordinates for IP %s: Latitude %f, Longitude %f\n", ipOfLB, coordinatesOfLB.Latitude, coordinatesOfLB.Longitude)
-
-	lbLat := coordinatesOfLB.Latitude.(float64)
-	lbLon := coordinatesOfLB.Longitude.(float64)
-
-	for _, server := range servers {
-		fmt.Println("\x1b[31mINFORMATION OF SERVER\x1b[0m")
-		parsedURL, err := url.Parse(server)
-		if err != nil {
-			log.Fatalf("Failed to parse URL %s: %v", server, err)
-		}
-		ip := parsedURL.Hostname()
-		coordinatesOfServer, err := getCoordinatesFromIP(ip)
-		fmt.Printf("Location for Server %s: Latitude %f, Longitude %f\n", ip, coordinatesOfServer.Latitude, coordinatesOfServer.Longitude)
-		if err != nil {
-			log.Fatalf("Failed to get coordinates for server %s: %v", ip, err)
-		}
+// parseBackendEntry splits a single -backends entry into its URL and
+// optional weight, e.g. "http://10.0.0.1:9000|3" -> (url, 3). Entries
+// without a weight suffix default to weight 1.
+func parseBackendEntry(entry string) (rawURL string, weight int) {
+	parts := strings.SplitN(entry, "|", 2)
+	if len(parts) == 1 {
+		return parts[0], 1
+	}
 
-		serverLat := coordinatesOfServer.Latitude.(float64)
-		serverLon := coordinatesOfServer.Longitude.(float64)
+	w, err := strconv.Atoi(strings.TrimSpace(parts[1]))
+	if err != nil || w <= 0 {
+		return parts[0], 1
+	}
+	return parts[0], w
+}
 
-		// Calculate distance between load balancer and server
-		distance := haversine(lbLat, lbLon, serverLat, serverLon)
-		fmt.Printf("Distance between load balancer and server %s: %.2f km\n", ip, distance)
+// remoteHost strips the port from a request's RemoteAddr, since the
+// per-client rate limiter should key off the client's address, not the
+// ephemeral source port of a single connection.
+func remoteHost(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
 
-		if distance <= 1 {
-			fmt.Printf("\x1b[32mServer status: Good\x1b[0m\n\n")
-		} else if distance <= 2 {
-			fmt.Printf("\x1b[33mServer status: advantage\x1b[0m\n\n")
+// wireProxyHooks hooks backend's circuit breaker, and optionally its passive
+// health check, into its reverse proxy: 5xx responses and proxy-level errors
+// (dial failures, timeouts, ...) count as failures, everything else counts
+// as a success. When passiveHealth is set, the same pass/fail signal is also
+// fed into backend.Health, so a backend can be marked unhealthy from
+// observed traffic alone, without waiting on the next active probe.
+func wireProxyHooks(backend *pool.Backend, passiveHealth bool, recorder metrics.Recorder) {
+	observe := func(success bool) {
+		if success {
+			backend.Breaker.RecordSuccess()
 		} else {
-			fmt.Printf("\x1b[31mServer status: Bad\x1b[0m\n\n")
+			backend.Breaker.RecordFailure()
+		}
+
+		if !passiveHealth || backend.Health == nil {
+			return
 		}
+		alive, transitioned := backend.Health.Observe(success)
+		if !transitioned {
+			return
+		}
+		label := backend.URL.String()
+		status := healthStatusLabel(alive)
+		recorder.SetHealthStatus(label, status)
+		log.Printf("health: backend %s transitioned to %s (mode=passive)\n", label, status)
 	}
 
-	log.Printf("Load Balancer started at :%d\n", port)
-	if err := server.ListenAndServe(); err != nil {
-		log.Fatal(err)
+	backend.ReverseProxy.ModifyResponse = func(resp *http.Response) error {
+		observe(resp.StatusCode < http.StatusInternalServerError)
+		return nil
+	}
+	backend.ReverseProxy.ErrorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
+		observe(false)
+		http.Error(w, "Bad Gateway", http.StatusBadGateway)
 	}
 }
 
-func getPublicIP() string {
-	resp, err := http.Get("https://api.ipify.org")
-	if err != nil {
-		log.Fatal("Failed to get public IP:", err)
+// healthStatusLabel renders a backend's alive state the way it is reported
+// through metrics and logs.
+func healthStatusLabel(alive bool) string {
+	if alive {
+		return "healthy"
 	}
-	defer resp.Body.Close()
+	return "unhealthy"
+}
 
-	ip, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		log.Fatal("Failed to read response:", err)
+// newProber builds the health.Prober named by protocol for serverURL.
+// "http" issues a request per the -health-* flags; "tcp" does a raw dial to
+// the backend's host:port.
+func newProber(protocol string, serverURL *url.URL, method, path string, expectStatus int, expectBody string, timeout time.Duration) (health.Prober, error) {
+	switch protocol {
+	case "http":
+		return health.HTTPProbe{
+			Target:       serverURL.String(),
+			Method:       method,
+			Path:         path,
+			ExpectStatus: expectStatus,
+			ExpectBody:   expectBody,
+			Timeout:      timeout,
+		}, nil
+	case "tcp":
+		return health.TCPProbe{Target: serverURL.Host, Timeout: timeout}, nil
+	default:
+		return nil, fmt.Errorf("unknown -health-protocol %q", protocol)
 	}
-
-	return string(ip)
 }
 
-func getCoordinatesFromIP(ip string) (Location, error) {
-	url := fmt.Sprintf("https://nominatim.openstreetmap.org/search?format=json&q=%s", ip)
-	resp, err := http.Get(url)
-	if err != nil {
-		return Location{}, err
+// newPolicy builds the Policy named by name. geoDB may be nil; it is only
+// required by the "geo-nearest" policy. vivaldiCoordsFile, when non-empty,
+// is where the "lowest-latency" policy persists its learned coordinate.
+func newPolicy(name string, geoDB *geoip.DB, vivaldiCoordsFile string) (pool.Policy, error) {
+	switch name {
+	case "round-robin":
+		return &pool.RoundRobinPolicy{}, nil
+	case "random":
+		return &pool.RandomPolicy{}, nil
+	case "least-connections":
+		return &pool.LeastConnectionsPolicy{}, nil
+	case "weighted":
+		return pool.NewWeightedRoundRobinPolicy(), nil
+	case "ip-hash":
+		return &pool.IPHashPolicy{}, nil
+	case "geo-nearest":
+		if geoDB == nil {
+			return nil, fmt.Errorf("-policy geo-nearest requires -geoip-db")
+		}
+		return pool.NewGeoNearestPolicy(geoDB, &pool.RandomPolicy{}), nil
+	case "lowest-latency":
+		var store *vivaldi.Store
+		if vivaldiCoordsFile != "" {
+			store = vivaldi.NewStore(vivaldiCoordsFile)
+		}
+		return pool.NewLowestLatencyPolicy(store), nil
+	default:
+		return nil, fmt.Errorf("unknown -policy %q", name)
 	}
-	defer resp.Body.Close()
+}
 
-	var locations []Location
-	err = json.NewDecoder(resp.Body).Decode(&locations)
-	if err != nil {
-		return Location{}, err
+// lbCoordinates resolves the load balancer's own location, used to report
+// the lb_distance_km metric. It returns ok=false when no GeoIP database was
+// loaded or the local outbound address can't be determined.
+func lbCoordinates(geoDB *geoip.DB) (lat, lon float64, ok bool) {
+	if geoDB == nil {
+		return 0, 0, false
 	}
 
-	if len(locations) == 0 {
-		return Location{}, fmt.Errorf("no coordinates found for IP %s", ip)
+	ip, err := localOutboundIP()
+	if err != nil {
+		return 0, 0, false
 	}
 
-	latitude := 0.0
-	switch v := locations[0].Latitude.(type) {
-	case float64:
-		latitude = v
-	case string:
-		lat, err := strconv.ParseFloat(v, 64)
-		if err != nil {
-			return Location{}, err
-		}
-		latitude = lat
-	}
+	return geoDB.Lookup(ip)
+}
 
-	longitude := 0.0
-	switch v := locations[0].Longitude.(type) {
-	case float64:
-		longitude = v
-	case string:
-		lon, err := strconv.ParseFloat(v, 64)
-		if err != nil {
-			return Location{}, err
-		}
-		longitude = lon
+// localOutboundIP returns the local address that would be used to reach the
+// public internet, without sending any packets: UDP "connect" only
+// resolves a route.
+func localOutboundIP() (net.IP, error) {
+	conn, err := net.Dial("udp", "8.8.8.8:80")
+	if err != nil {
+		return nil, err
 	}
+	defer conn.Close()
 
-	return Location{
-		Latitude:  latitude,
-		Longitude: longitude,
-	}, nil
+	return conn.LocalAddr().(*net.UDPAddr).IP, nil
 }
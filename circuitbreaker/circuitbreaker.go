@@ -0,0 +1,194 @@
+// Package circuitbreaker implements a passive circuit breaker driven by
+// observed request outcomes (5xx responses, proxy errors) rather than an
+// out-of-band probe, so a backend that starts failing under load is pulled
+// out of rotation faster than the next active health check would notice.
+package circuitbreaker
+
+import (
+	"sync"
+	"time"
+)
+
+// State is the breaker's current disposition toward its backend.
+type State int
+
+const (
+	// Closed admits every request and tracks the rolling error rate.
+	Closed State = iota
+	// Open rejects every request until Cooldown elapses.
+	Open
+	// HalfOpen admits exactly one probe request to decide whether to
+	// return to Closed or back to Open.
+	HalfOpen
+)
+
+func (s State) String() string {
+	switch s {
+	case Closed:
+		return "closed"
+	case Open:
+		return "open"
+	case HalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+type outcome struct {
+	at      time.Time
+	success bool
+}
+
+// Breaker tracks a rolling error rate over Window and trips to Open once
+// ErrorThreshold is exceeded (given at least MinSamples observations),
+// staying there for Cooldown before allowing a single half-open probe.
+type Breaker struct {
+	ErrorThreshold float64
+	Window         time.Duration
+	Cooldown       time.Duration
+	MinSamples     int
+
+	mu               sync.Mutex
+	state            State
+	history          []outcome
+	openedAt         time.Time
+	halfOpenIssuedAt time.Time
+}
+
+// New builds a Breaker with the given tuning. MinSamples is set to 5, which
+// guards against tripping on a handful of cold-start requests.
+func New(errorThreshold float64, window, cooldown time.Duration) *Breaker {
+	return &Breaker{
+		ErrorThreshold: errorThreshold,
+		Window:         window,
+		Cooldown:       cooldown,
+		MinSamples:     5,
+	}
+}
+
+// Allow reports whether a request should be routed to this backend right
+// now. In the Open state it also handles the transition to HalfOpen once
+// Cooldown has elapsed, admitting exactly one probe.
+func (b *Breaker) Allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case Closed:
+		return true
+	case Open:
+		if time.Since(b.openedAt) < b.Cooldown {
+			return false
+		}
+		b.state = HalfOpen
+		b.halfOpenIssuedAt = time.Now()
+		return true
+	case HalfOpen:
+		// A half-open probe that never resolves (e.g. it was admitted here
+		// but a different backend ended up serving the request) shouldn't
+		// wedge the breaker open forever; give up on it after a cooldown
+		// and issue a fresh probe.
+		if time.Since(b.halfOpenIssuedAt) > b.Cooldown {
+			b.halfOpenIssuedAt = time.Now()
+			return true
+		}
+		return false
+	default:
+		return true
+	}
+}
+
+// Probeable reports whether this backend is currently worth considering for
+// a request, without admitting one: true in Closed, true in HalfOpen (a
+// probe may already be in flight), and true in Open once Cooldown has
+// elapsed (a probe is due). Unlike Allow, it never transitions state or
+// consumes the single half-open probe slot - callers building a candidate
+// list from many backends should use this, and reserve Allow for the one
+// backend they actually route a request to.
+func (b *Breaker) Probeable() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case Open:
+		return time.Since(b.openedAt) >= b.Cooldown
+	default:
+		return true
+	}
+}
+
+// RecordSuccess reports a request that completed without a server error.
+func (b *Breaker) RecordSuccess() {
+	b.record(true)
+}
+
+// RecordFailure reports a 5xx response or a proxy-level error (dial
+// failure, timeout, ...).
+func (b *Breaker) RecordFailure() {
+	b.record(false)
+}
+
+func (b *Breaker) record(success bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == HalfOpen {
+		if success {
+			b.state = Closed
+			b.history = nil
+		} else {
+			b.trip()
+		}
+		return
+	}
+
+	now := time.Now()
+	b.history = append(b.history, outcome{at: now, success: success})
+	b.history = b.prune(b.history, now)
+
+	if len(b.history) < b.MinSamples {
+		return
+	}
+
+	if b.errorRate(b.history) > b.ErrorThreshold {
+		b.trip()
+	}
+}
+
+// State returns the breaker's current state without side effects.
+func (b *Breaker) State() State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state
+}
+
+func (b *Breaker) trip() {
+	b.state = Open
+	b.openedAt = time.Now()
+	b.history = nil
+}
+
+func (b *Breaker) prune(history []outcome, now time.Time) []outcome {
+	cutoff := now.Add(-b.Window)
+	kept := history[:0]
+	for _, o := range history {
+		if o.at.After(cutoff) {
+			kept = append(kept, o)
+		}
+	}
+	return kept
+}
+
+func (b *Breaker) errorRate(history []outcome) float64 {
+	if len(history) == 0 {
+		return 0
+	}
+	errors := 0
+	for _, o := range history {
+		if !o.success {
+			errors++
+		}
+	}
+	return float64(errors) / float64(len(history))
+}
@@ -0,0 +1,121 @@
+package circuitbreaker
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBreakerTripsAfterErrorThreshold(t *testing.T) {
+	b := New(0.5, time.Minute, time.Hour)
+
+	for i := 0; i < 4; i++ {
+		b.RecordSuccess()
+	}
+	if got := b.State(); got != Closed {
+		t.Fatalf("state = %v, want Closed after only successes", got)
+	}
+
+	for i := 0; i < 5; i++ {
+		b.RecordFailure()
+	}
+	if got := b.State(); got != Open {
+		t.Fatalf("state = %v, want Open once the error rate exceeds threshold", got)
+	}
+	if b.Allow() {
+		t.Fatal("Allow returned true while Open and within the cooldown")
+	}
+}
+
+func TestBreakerHalfOpenAdmitsSingleProbe(t *testing.T) {
+	b := New(0.5, time.Minute, time.Millisecond)
+	for i := 0; i < 5; i++ {
+		b.RecordFailure()
+	}
+	if got := b.State(); got != Open {
+		t.Fatalf("state = %v, want Open", got)
+	}
+
+	time.Sleep(2 * time.Millisecond)
+
+	if !b.Allow() {
+		t.Fatal("expected the first Allow after cooldown to admit a half-open probe")
+	}
+	if got := b.State(); got != HalfOpen {
+		t.Fatalf("state = %v, want HalfOpen after admitting a probe", got)
+	}
+	if b.Allow() {
+		t.Fatal("expected a second concurrent Allow to be rejected while a probe is in flight")
+	}
+}
+
+func TestBreakerClosesOnSuccessfulProbe(t *testing.T) {
+	b := New(0.5, time.Minute, time.Millisecond)
+	for i := 0; i < 5; i++ {
+		b.RecordFailure()
+	}
+	time.Sleep(2 * time.Millisecond)
+	if !b.Allow() {
+		t.Fatal("expected the probe to be admitted")
+	}
+
+	b.RecordSuccess()
+	if got := b.State(); got != Closed {
+		t.Fatalf("state = %v, want Closed after a successful probe", got)
+	}
+}
+
+func TestBreakerReopensOnFailedProbe(t *testing.T) {
+	b := New(0.5, time.Minute, time.Millisecond)
+	for i := 0; i < 5; i++ {
+		b.RecordFailure()
+	}
+	time.Sleep(2 * time.Millisecond)
+	if !b.Allow() {
+		t.Fatal("expected the probe to be admitted")
+	}
+
+	b.RecordFailure()
+	if got := b.State(); got != Open {
+		t.Fatalf("state = %v, want Open after a failed probe", got)
+	}
+}
+
+func TestBreakerIgnoresColdStartBelowMinSamples(t *testing.T) {
+	b := New(0.1, time.Minute, time.Hour)
+	b.RecordFailure()
+	b.RecordFailure()
+	if got := b.State(); got != Closed {
+		t.Fatalf("state = %v, want Closed with fewer than MinSamples observations", got)
+	}
+}
+
+func TestProbeableDoesNotConsumeHalfOpenSlot(t *testing.T) {
+	b := New(0.5, time.Minute, time.Millisecond)
+	for i := 0; i < 5; i++ {
+		b.RecordFailure()
+	}
+
+	if b.Probeable() {
+		t.Fatal("expected Probeable to be false while Open and within the cooldown")
+	}
+
+	time.Sleep(2 * time.Millisecond)
+	for i := 0; i < 3; i++ {
+		if !b.Probeable() {
+			t.Fatal("expected Probeable to be true once the cooldown has elapsed")
+		}
+	}
+	if got := b.State(); got != Open {
+		t.Fatalf("state = %v, want still Open - Probeable must never transition state", got)
+	}
+
+	if !b.Allow() {
+		t.Fatal("expected Allow to admit the probe Probeable reported as due")
+	}
+	if got := b.State(); got != HalfOpen {
+		t.Fatalf("state = %v, want HalfOpen after Allow actually admitted a probe", got)
+	}
+	if !b.Probeable() {
+		t.Fatal("expected Probeable to stay true while a probe is in flight")
+	}
+}
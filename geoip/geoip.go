@@ -0,0 +1,70 @@
+// Package geoip provides offline IP geolocation backed by a MaxMind
+// GeoLite2/GeoIP2 City database (.mmdb), used to route requests to the
+// geographically nearest backend without relying on a remote API.
+package geoip
+
+import (
+	"math"
+	"net"
+
+	"github.com/oschwald/geoip2-golang"
+)
+
+// DB wraps an open MaxMind database.
+type DB struct {
+	reader *geoip2.Reader
+}
+
+// Open reads the .mmdb file at path into memory and returns a ready DB.
+// Callers are responsible for calling Close when done.
+func Open(path string) (*DB, error) {
+	reader, err := geoip2.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	return &DB{reader: reader}, nil
+}
+
+// Close releases the underlying database file.
+func (d *DB) Close() error {
+	return d.reader.Close()
+}
+
+// Lookup resolves ip to a latitude/longitude pair. ok is false when the
+// database has no location for ip (private/reserved ranges, unknown IPs,
+// or records missing coordinates).
+func (d *DB) Lookup(ip net.IP) (lat, lon float64, ok bool) {
+	if d == nil || ip == nil {
+		return 0, 0, false
+	}
+
+	record, err := d.reader.City(ip)
+	if err != nil {
+		return 0, 0, false
+	}
+
+	if record.Location.Latitude == 0 && record.Location.Longitude == 0 {
+		return 0, 0, false
+	}
+
+	return record.Location.Latitude, record.Location.Longitude, true
+}
+
+// Haversine returns the great-circle distance in kilometers between two
+// latitude/longitude pairs given in degrees.
+func Haversine(lat1, lon1, lat2, lon2 float64) float64 {
+	const earthRadius = 6371
+
+	lat1Rad := lat1 * (math.Pi / 180)
+	lon1Rad := lon1 * (math.Pi / 180)
+	lat2Rad := lat2 * (math.Pi / 180)
+	lon2Rad := lon2 * (math.Pi / 180)
+
+	deltaLat := lat2Rad - lat1Rad
+	deltaLon := lon2Rad - lon1Rad
+
+	a := math.Pow(math.Sin(deltaLat/2), 2) + math.Cos(lat1Rad)*math.Cos(lat2Rad)*math.Pow(math.Sin(deltaLon/2), 2)
+	c := 2 * math.Atan2(math.Sqrt(a), math.Sqrt(1-a))
+
+	return earthRadius * c
+}
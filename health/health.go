@@ -0,0 +1,191 @@
+// Package health implements active and passive backend health checking: a
+// Checker turns a stream of pass/fail probe results, from either a
+// configurable Prober or observed proxy traffic, into a debounced alive/dead
+// state that only flips after a run of consecutive results in the same
+// direction.
+package health
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Result is the outcome of a single probe.
+type Result struct {
+	// Healthy reports whether the probe passed.
+	Healthy bool
+	// RTT is how long the probe took to resolve, success or failure.
+	RTT time.Duration
+	// Err explains why Healthy is false. Nil when Healthy is true.
+	Err error
+}
+
+// Prober runs a single health probe against a backend.
+type Prober interface {
+	Probe() Result
+}
+
+// HTTPProbe probes a backend by issuing an HTTP request and checking its
+// status code and, optionally, a body substring.
+type HTTPProbe struct {
+	// Target is the backend's base URL, e.g. "http://10.0.0.1:9000".
+	Target string
+	// Method defaults to GET when empty.
+	Method string
+	// Path is appended to Target, e.g. "/healthz".
+	Path string
+	// ExpectStatus is the required response status code. Zero skips the
+	// check and accepts any status.
+	ExpectStatus int
+	// ExpectBody, when non-empty, must appear as a substring of the
+	// response body.
+	ExpectBody string
+	Timeout    time.Duration
+}
+
+// Probe implements Prober.
+func (p HTTPProbe) Probe() Result {
+	method := p.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+
+	req, err := http.NewRequest(method, p.Target+p.Path, nil)
+	if err != nil {
+		return Result{Err: err}
+	}
+
+	client := http.Client{Timeout: p.Timeout}
+	start := time.Now()
+	resp, err := client.Do(req)
+	rtt := time.Since(start)
+	if err != nil {
+		return Result{RTT: rtt, Err: err}
+	}
+	defer resp.Body.Close()
+
+	if p.ExpectStatus != 0 && resp.StatusCode != p.ExpectStatus {
+		return Result{RTT: rtt, Err: fmt.Errorf("unexpected status %d, want %d", resp.StatusCode, p.ExpectStatus)}
+	}
+
+	if p.ExpectBody != "" {
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return Result{RTT: rtt, Err: err}
+		}
+		if !bytes.Contains(body, []byte(p.ExpectBody)) {
+			return Result{RTT: rtt, Err: fmt.Errorf("response body did not contain %q", p.ExpectBody)}
+		}
+	}
+
+	return Result{Healthy: true, RTT: rtt}
+}
+
+// TCPProbe probes a backend with a raw TCP dial, without inspecting any
+// application-level response.
+type TCPProbe struct {
+	// Target is the backend's host:port.
+	Target  string
+	Timeout time.Duration
+}
+
+// Probe implements Prober.
+func (p TCPProbe) Probe() Result {
+	start := time.Now()
+	conn, err := net.DialTimeout("tcp", p.Target, p.Timeout)
+	rtt := time.Since(start)
+	if err != nil {
+		return Result{RTT: rtt, Err: err}
+	}
+	conn.Close()
+	return Result{Healthy: true, RTT: rtt}
+}
+
+// Checker debounces a backend's reported alive state against a run of
+// consecutive probe results: it takes HealthyThreshold consecutive passes to
+// go alive and UnhealthyThreshold consecutive failures to go down. Results
+// can come from Check, which runs Prober once, or from Observe, which lets a
+// passive signal (e.g. a proxied response's status code) feed the same state
+// machine without an out-of-band probe. Callers that probe on an interval
+// (the usual case) drive that cadence themselves and call Check on each
+// tick; Checker itself is not a scheduler. A Checker is the single source of
+// truth for its backend's alive state - callers should read it via Alive
+// rather than mirroring it into their own flag, since Check and Observe are
+// commonly called concurrently from an active-probe loop and the reverse
+// proxy's response hook.
+type Checker struct {
+	Prober             Prober
+	UnhealthyThreshold int
+	HealthyThreshold   int
+
+	mu          sync.Mutex
+	alive       bool
+	consecutive int // positive: streak of passes, negative: streak of failures
+}
+
+// NewChecker builds a Checker that starts out optimistically alive, so a
+// backend isn't rejected by every policy before its first probe completes.
+func NewChecker(prober Prober, unhealthyThreshold, healthyThreshold int) *Checker {
+	return &Checker{
+		Prober:             prober,
+		UnhealthyThreshold: unhealthyThreshold,
+		HealthyThreshold:   healthyThreshold,
+		alive:              true,
+	}
+}
+
+// Check runs one probe via Prober and folds its result into the checker's
+// state. It reports the raw Result alongside the state after folding it in,
+// and whether this probe flipped that state.
+func (c *Checker) Check() (result Result, alive bool, transitioned bool) {
+	result = c.Prober.Probe()
+	alive, transitioned = c.observe(result.Healthy)
+	return result, alive, transitioned
+}
+
+// Observe feeds a passive pass/fail signal into the same consecutive-
+// threshold state machine as Check, without running Prober. It lets a
+// backend be marked unhealthy from observed traffic alone, without waiting
+// on the next active probe.
+func (c *Checker) Observe(healthy bool) (alive bool, transitioned bool) {
+	return c.observe(healthy)
+}
+
+// Alive returns the checker's current folded state without recording a new
+// result.
+func (c *Checker) Alive() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.alive
+}
+
+func (c *Checker) observe(healthy bool) (alive bool, transitioned bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	prev := c.alive
+	if healthy {
+		if c.consecutive < 0 {
+			c.consecutive = 0
+		}
+		c.consecutive++
+		if !c.alive && c.consecutive >= c.HealthyThreshold {
+			c.alive = true
+		}
+	} else {
+		if c.consecutive > 0 {
+			c.consecutive = 0
+		}
+		c.consecutive--
+		if c.alive && -c.consecutive >= c.UnhealthyThreshold {
+			c.alive = false
+		}
+	}
+
+	return c.alive, c.alive != prev
+}
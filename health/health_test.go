@@ -0,0 +1,76 @@
+package health
+
+import "testing"
+
+func TestCheckerStartsAlive(t *testing.T) {
+	c := NewChecker(nil, 3, 2)
+	if !c.Alive() {
+		t.Fatal("expected a fresh Checker to start alive")
+	}
+}
+
+func TestCheckerFlipsAfterConsecutiveFailures(t *testing.T) {
+	c := NewChecker(nil, 3, 2)
+
+	if alive, transitioned := c.Observe(false); !alive || transitioned {
+		t.Fatalf("alive = %v, transitioned = %v after 1 failure, want alive, no transition", alive, transitioned)
+	}
+	if alive, transitioned := c.Observe(false); !alive || transitioned {
+		t.Fatalf("alive = %v, transitioned = %v after 2 failures, want alive, no transition", alive, transitioned)
+	}
+	alive, transitioned := c.Observe(false)
+	if alive || !transitioned {
+		t.Fatalf("alive = %v, transitioned = %v after 3 failures, want unhealthy, transitioned", alive, transitioned)
+	}
+	if c.Alive() {
+		t.Fatal("expected Checker to report unhealthy after reaching UnhealthyThreshold")
+	}
+}
+
+func TestCheckerRequiresConsecutiveFailures(t *testing.T) {
+	c := NewChecker(nil, 3, 2)
+
+	c.Observe(false)
+	c.Observe(false)
+	c.Observe(true) // resets the failure streak
+	c.Observe(false)
+	c.Observe(false)
+
+	if !c.Alive() {
+		t.Fatal("expected an interrupted failure streak not to trip the threshold")
+	}
+}
+
+func TestCheckerRecoversAfterConsecutivePasses(t *testing.T) {
+	c := NewChecker(nil, 3, 2)
+	c.Observe(false)
+	c.Observe(false)
+	c.Observe(false)
+	if c.Alive() {
+		t.Fatal("expected Checker to be unhealthy before recovery")
+	}
+
+	if alive, transitioned := c.Observe(true); alive || transitioned {
+		t.Fatalf("alive = %v, transitioned = %v after 1 pass, want still unhealthy", alive, transitioned)
+	}
+	alive, transitioned := c.Observe(true)
+	if !alive || !transitioned {
+		t.Fatalf("alive = %v, transitioned = %v after 2 passes, want healthy, transitioned", alive, transitioned)
+	}
+}
+
+func TestCheckUsesProber(t *testing.T) {
+	c := NewChecker(stubProber{result: Result{Healthy: true}}, 3, 2)
+	result, alive, transitioned := c.Check()
+	if !result.Healthy || !alive || transitioned {
+		t.Fatalf("Check() = (%+v, %v, %v), want a healthy passthrough with no transition", result, alive, transitioned)
+	}
+}
+
+type stubProber struct {
+	result Result
+}
+
+func (s stubProber) Probe() Result {
+	return s.result
+}
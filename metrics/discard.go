@@ -0,0 +1,15 @@
+package metrics
+
+import "time"
+
+// discardRecorder implements Recorder by dropping every measurement. It is
+// the default sink so running the balancer without -metrics-backend costs
+// nothing.
+type discardRecorder struct{}
+
+func (discardRecorder) IncRequest(backend string)                      {}
+func (discardRecorder) ObserveLatency(backend string, d time.Duration) {}
+func (discardRecorder) SetActiveConnections(backend string, n int64)   {}
+func (discardRecorder) SetHealthStatus(backend, status string)         {}
+func (discardRecorder) IncHealthCheck(backend string, passed bool)     {}
+func (discardRecorder) SetDistance(backend string, km float64)         {}
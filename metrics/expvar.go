@@ -0,0 +1,98 @@
+package metrics
+
+import (
+	"expvar"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// expvarRecorder publishes metrics under the standard expvar namespace,
+// one expvar.Map per measurement keyed by backend URL.
+type expvarRecorder struct {
+	requestsTotal     *expvar.Map
+	latencySumMillis  *expvar.Map
+	activeConnections *expvar.Map
+	healthStatus      *expvar.Map
+	healthChecksPass  *expvar.Map
+	healthChecksFail  *expvar.Map
+	distanceKm        *expvar.Map
+
+	mu     sync.Mutex
+	floats map[string]*expvar.Float
+}
+
+func newExpvarRecorder() *expvarRecorder {
+	return &expvarRecorder{
+		requestsTotal:     expvar.NewMap("lb_requests_total"),
+		latencySumMillis:  expvar.NewMap("lb_latency_sum_millis"),
+		activeConnections: expvar.NewMap("lb_active_connections"),
+		healthStatus:      expvar.NewMap("lb_health_status"),
+		healthChecksPass:  expvar.NewMap("lb_health_checks_pass_total"),
+		healthChecksFail:  expvar.NewMap("lb_health_checks_fail_total"),
+		distanceKm:        expvar.NewMap("lb_distance_km"),
+		floats:            make(map[string]*expvar.Float),
+	}
+}
+
+// newExpvarHandler returns the standard expvar HTTP handler, which dumps
+// every published variable (not just this package's) as JSON.
+func newExpvarHandler() http.Handler {
+	return expvar.Handler()
+}
+
+func (r *expvarRecorder) IncRequest(backend string) {
+	r.requestsTotal.Add(backend, 1)
+}
+
+func (r *expvarRecorder) ObserveLatency(backend string, d time.Duration) {
+	r.latencySumMillis.AddFloat(backend, float64(d.Milliseconds()))
+}
+
+func (r *expvarRecorder) SetActiveConnections(backend string, n int64) {
+	r.floatVar(r.activeConnections, "lb_active_connections", backend).Set(float64(n))
+}
+
+func (r *expvarRecorder) SetHealthStatus(backend, status string) {
+	r.floatVar(r.healthStatus, "lb_health_status", backend).Set(healthStatusValue(status))
+}
+
+func (r *expvarRecorder) IncHealthCheck(backend string, passed bool) {
+	if passed {
+		r.healthChecksPass.Add(backend, 1)
+	} else {
+		r.healthChecksFail.Add(backend, 1)
+	}
+}
+
+func (r *expvarRecorder) SetDistance(backend string, km float64) {
+	r.floatVar(r.distanceKm, "lb_distance_km", backend).Set(km)
+}
+
+// floatVar returns the expvar.Float for key within m, creating it on first
+// use. expvar.Map has no typed gauge accessor, so we cache the *expvar.Float
+// ourselves (keyed by the map's own variable name plus key) and register it
+// with m exactly once.
+func (r *expvarRecorder) floatVar(m *expvar.Map, varName, key string) *expvar.Float {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	cacheKey := varName + "\x00" + key
+	if f, ok := r.floats[cacheKey]; ok {
+		return f
+	}
+
+	f := new(expvar.Float)
+	m.Set(key, f)
+	r.floats[cacheKey] = f
+	return f
+}
+
+// healthStatusValue encodes a health.Checker's alive state as a number so it
+// can be charted like any other gauge.
+func healthStatusValue(status string) float64 {
+	if status == "healthy" {
+		return 1
+	}
+	return 0
+}
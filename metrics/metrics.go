@@ -0,0 +1,63 @@
+// Package metrics instruments the load balancer with request, latency,
+// health, and connection metrics, exposed through a pluggable sink so the
+// balancer can run standalone or be embedded as a library with a
+// caller-supplied Builder.
+package metrics
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Recorder records everything the load balancer measures about itself.
+// All methods must be safe for concurrent use.
+type Recorder interface {
+	// IncRequest counts one proxied request to backend.
+	IncRequest(backend string)
+	// ObserveLatency records how long a request to backend took.
+	ObserveLatency(backend string, d time.Duration)
+	// SetActiveConnections reports the current in-flight request count for
+	// backend.
+	SetActiveConnections(backend string, n int64)
+	// SetHealthStatus records backend's current alive state as reported by
+	// the health package, "healthy" or "unhealthy", after an active probe
+	// or a passive observation flips it.
+	SetHealthStatus(backend, status string)
+	// IncHealthCheck counts one active health-check probe result for
+	// backend.
+	IncHealthCheck(backend string, passed bool)
+	// SetDistance records the current geographic distance, in kilometers,
+	// between the load balancer and backend.
+	SetDistance(backend string, km float64)
+}
+
+// Builder produces a Recorder and the HTTP handler that exposes it.
+// Embedders of this package as a library can supply their own Builder to
+// plug in a metrics sink other than the ones shipped here.
+type Builder interface {
+	Build() (Recorder, http.Handler, error)
+}
+
+// MetricsBuilder is the default Builder, selecting between the sinks
+// shipped with this package via Backend, which is normally populated from
+// the -metrics-backend flag.
+type MetricsBuilder struct {
+	// Backend is one of "discard", "expvar", or "prometheus". An empty
+	// value is treated as "discard".
+	Backend string
+}
+
+// Build resolves Backend to a concrete Recorder and its /metrics handler.
+func (b MetricsBuilder) Build() (Recorder, http.Handler, error) {
+	switch b.Backend {
+	case "", "discard":
+		return discardRecorder{}, http.NotFoundHandler(), nil
+	case "expvar":
+		return newExpvarRecorder(), newExpvarHandler(), nil
+	case "prometheus":
+		return newPrometheusRecorder()
+	default:
+		return nil, nil, fmt.Errorf("unknown -metrics-backend %q", b.Backend)
+	}
+}
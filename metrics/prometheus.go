@@ -0,0 +1,93 @@
+package metrics
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// prometheusRecorder publishes metrics as standard Prometheus collectors on
+// a private registry, so embedding this package doesn't pollute the
+// process-wide default registry.
+type prometheusRecorder struct {
+	requestsTotal     *prometheus.CounterVec
+	latencySeconds    *prometheus.HistogramVec
+	activeConnections *prometheus.GaugeVec
+	healthStatus      *prometheus.GaugeVec
+	healthChecksTotal *prometheus.CounterVec
+	distanceKm        *prometheus.GaugeVec
+}
+
+func newPrometheusRecorder() (Recorder, http.Handler, error) {
+	registry := prometheus.NewRegistry()
+
+	r := &prometheusRecorder{
+		requestsTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "lb_requests_total",
+			Help: "Total requests proxied per backend.",
+		}, []string{"backend"}),
+		latencySeconds: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "lb_request_duration_seconds",
+			Help:    "Request duration per backend.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"backend"}),
+		activeConnections: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "lb_active_connections",
+			Help: "In-flight requests per backend.",
+		}, []string{"backend"}),
+		healthStatus: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "lb_health_status",
+			Help: "Current alive state per backend (1=healthy, 0=unhealthy).",
+		}, []string{"backend"}),
+		healthChecksTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "lb_health_checks_total",
+			Help: "Health-check results per backend.",
+		}, []string{"backend", "result"}),
+		distanceKm: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "lb_distance_km",
+			Help: "Geographic distance between the load balancer and each backend, in kilometers.",
+		}, []string{"backend"}),
+	}
+
+	registry.MustRegister(
+		r.requestsTotal,
+		r.latencySeconds,
+		r.activeConnections,
+		r.healthStatus,
+		r.healthChecksTotal,
+		r.distanceKm,
+	)
+
+	handler := promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+	return r, handler, nil
+}
+
+func (r *prometheusRecorder) IncRequest(backend string) {
+	r.requestsTotal.WithLabelValues(backend).Inc()
+}
+
+func (r *prometheusRecorder) ObserveLatency(backend string, d time.Duration) {
+	r.latencySeconds.WithLabelValues(backend).Observe(d.Seconds())
+}
+
+func (r *prometheusRecorder) SetActiveConnections(backend string, n int64) {
+	r.activeConnections.WithLabelValues(backend).Set(float64(n))
+}
+
+func (r *prometheusRecorder) SetHealthStatus(backend, status string) {
+	r.healthStatus.WithLabelValues(backend).Set(healthStatusValue(status))
+}
+
+func (r *prometheusRecorder) IncHealthCheck(backend string, passed bool) {
+	result := "fail"
+	if passed {
+		result = "pass"
+	}
+	r.healthChecksTotal.WithLabelValues(backend, result).Inc()
+}
+
+func (r *prometheusRecorder) SetDistance(backend string, km float64) {
+	r.distanceKm.WithLabelValues(backend).Set(km)
+}
@@ -0,0 +1,71 @@
+package pool
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/NghiaaPD/Load-Balancer-ResFes/vivaldi"
+)
+
+// RTTObserver is implemented by policies that want to learn from measured
+// round-trip times, such as LowestLatencyPolicy. ServerPool doesn't call
+// this itself; callers that run their own health-check loop should feed
+// samples in via a type assertion against the configured Policy.
+type RTTObserver interface {
+	RecordRTT(backend *Backend, rtt time.Duration)
+}
+
+// LowestLatencyPolicy routes each request to the backend with the smallest
+// predicted round-trip time, using Vivaldi network coordinates calibrated
+// from health-check RTT samples instead of a single static classification.
+type LowestLatencyPolicy struct {
+	mu    sync.Mutex
+	local vivaldi.Coordinate
+	store *vivaldi.Store
+}
+
+// NewLowestLatencyPolicy builds a LowestLatencyPolicy. store may be nil, in
+// which case the learned coordinate is not persisted and calibration
+// starts from scratch on every run.
+func NewLowestLatencyPolicy(store *vivaldi.Store) *LowestLatencyPolicy {
+	local := vivaldi.NewCoordinate()
+	if store != nil {
+		if loaded, ok := store.Load(); ok {
+			local = loaded
+		}
+	}
+	return &LowestLatencyPolicy{local: local, store: store}
+}
+
+// RecordRTT folds a freshly measured round-trip time to backend into the
+// policy's coordinate.
+func (p *LowestLatencyPolicy) RecordRTT(backend *Backend, rtt time.Duration) {
+	p.mu.Lock()
+	p.local = p.local.Update(backend.Coord, rtt)
+	local := p.local
+	p.mu.Unlock()
+
+	if p.store != nil {
+		// Best-effort: a failed write shouldn't take routing down.
+		_ = p.store.Save(local)
+	}
+}
+
+func (p *LowestLatencyPolicy) Select(req *http.Request, healthy []*Backend) *Backend {
+	p.mu.Lock()
+	local := p.local
+	p.mu.Unlock()
+
+	best := healthy[0]
+	bestDistance := local.DistanceTo(best.Coord)
+
+	for _, backend := range healthy[1:] {
+		distance := local.DistanceTo(backend.Coord)
+		if distance < bestDistance {
+			best = backend
+			bestDistance = distance
+		}
+	}
+	return best
+}
@@ -0,0 +1,158 @@
+package pool
+
+import (
+	"hash/fnv"
+	"math"
+	"math/rand"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/NghiaaPD/Load-Balancer-ResFes/geoip"
+)
+
+// RoundRobinPolicy cycles through the healthy backends in order.
+type RoundRobinPolicy struct {
+	current int64
+}
+
+func (p *RoundRobinPolicy) Select(req *http.Request, healthy []*Backend) *Backend {
+	next := atomic.AddInt64(&p.current, 1)
+	return healthy[next%int64(len(healthy))]
+}
+
+// RandomPolicy picks a uniformly random healthy backend.
+type RandomPolicy struct{}
+
+func (p *RandomPolicy) Select(req *http.Request, healthy []*Backend) *Backend {
+	return healthy[rand.Intn(len(healthy))]
+}
+
+// LeastConnectionsPolicy picks the healthy backend with the fewest
+// in-flight requests, as tracked by Backend.ActiveConnections.
+type LeastConnectionsPolicy struct{}
+
+func (p *LeastConnectionsPolicy) Select(req *http.Request, healthy []*Backend) *Backend {
+	least := healthy[0]
+	leastConns := atomic.LoadInt64(&least.ActiveConnections)
+
+	for _, backend := range healthy[1:] {
+		conns := atomic.LoadInt64(&backend.ActiveConnections)
+		if conns < leastConns {
+			least = backend
+			leastConns = conns
+		}
+	}
+	return least
+}
+
+// WeightedRoundRobinPolicy distributes requests across backends in
+// proportion to their configured Weight, using the smooth weighted
+// round-robin algorithm (as used by nginx upstream).
+type WeightedRoundRobinPolicy struct {
+	mu      sync.Mutex
+	current map[*Backend]int
+}
+
+func NewWeightedRoundRobinPolicy() *WeightedRoundRobinPolicy {
+	return &WeightedRoundRobinPolicy{current: make(map[*Backend]int)}
+}
+
+func (p *WeightedRoundRobinPolicy) Select(req *http.Request, healthy []*Backend) *Backend {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	total := 0
+	var best *Backend
+	bestWeight := math.MinInt
+
+	for _, backend := range healthy {
+		weight := backend.Weight
+		if weight <= 0 {
+			weight = 1
+		}
+		total += weight
+
+		p.current[backend] += weight
+		if p.current[backend] > bestWeight {
+			best = backend
+			bestWeight = p.current[backend]
+		}
+	}
+
+	p.current[best] -= total
+	return best
+}
+
+// IPHashPolicy routes a given client IP to the same backend as long as the
+// set of healthy backends doesn't change, giving sticky sessions without
+// server-side state.
+type IPHashPolicy struct{}
+
+func (p *IPHashPolicy) Select(req *http.Request, healthy []*Backend) *Backend {
+	h := fnv.New32a()
+	h.Write([]byte(clientIP(req).String()))
+	index := h.Sum32() % uint32(len(healthy))
+	return healthy[index]
+}
+
+// GeoNearestPolicy routes each request to the healthy backend physically
+// closest to the client, using a GeoIP database to resolve both the
+// client's and the backends' coordinates.
+type GeoNearestPolicy struct {
+	db       *geoip.DB
+	fallback Policy
+}
+
+// NewGeoNearestPolicy builds a GeoNearestPolicy backed by db. fallback is
+// used when the client's location can't be resolved, or none of the
+// healthy backends have known coordinates.
+func NewGeoNearestPolicy(db *geoip.DB, fallback Policy) *GeoNearestPolicy {
+	return &GeoNearestPolicy{db: db, fallback: fallback}
+}
+
+func (p *GeoNearestPolicy) Select(req *http.Request, healthy []*Backend) *Backend {
+	clientLat, clientLon, ok := p.db.Lookup(clientIP(req))
+	if !ok {
+		return p.fallback.Select(req, healthy)
+	}
+
+	var closest *Backend
+	var closestDistance float64
+
+	for _, backend := range healthy {
+		if !backend.HasCoords {
+			continue
+		}
+		distance := geoip.Haversine(clientLat, clientLon, backend.Latitude, backend.Longitude)
+		if closest == nil || distance < closestDistance {
+			closest = backend
+			closestDistance = distance
+		}
+	}
+
+	if closest == nil {
+		return p.fallback.Select(req, healthy)
+	}
+	return closest
+}
+
+// clientIP extracts the originating client address from a request,
+// preferring the first hop recorded in X-Forwarded-For (when present)
+// over the immediate RemoteAddr, which is only the last proxy hop.
+func clientIP(req *http.Request) net.IP {
+	if forwarded := req.Header.Get("X-Forwarded-For"); forwarded != "" {
+		first := strings.TrimSpace(strings.Split(forwarded, ",")[0])
+		if ip := net.ParseIP(first); ip != nil {
+			return ip
+		}
+	}
+
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		host = req.RemoteAddr
+	}
+	return net.ParseIP(host)
+}
@@ -0,0 +1,185 @@
+package pool
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/NghiaaPD/Load-Balancer-ResFes/circuitbreaker"
+)
+
+func testBackend(t *testing.T, rawURL string, weight int) *Backend {
+	t.Helper()
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		t.Fatalf("parse %q: %v", rawURL, err)
+	}
+	b := &Backend{URL: u, Weight: weight}
+	b.SetAlive(true)
+	return b
+}
+
+func newRequest(remoteAddr string) *http.Request {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = remoteAddr
+	return req
+}
+
+func TestRoundRobinPolicyCyclesBackends(t *testing.T) {
+	backends := []*Backend{
+		testBackend(t, "http://a", 1),
+		testBackend(t, "http://b", 1),
+		testBackend(t, "http://c", 1),
+	}
+	policy := &RoundRobinPolicy{}
+	req := newRequest("10.0.0.1:1234")
+
+	var order []*Backend
+	for i := 0; i < 6; i++ {
+		order = append(order, policy.Select(req, backends))
+	}
+
+	for i, backend := range order {
+		want := backends[(i+1)%len(backends)]
+		if backend != want {
+			t.Fatalf("pick %d: got %v, want %v", i, backend.URL, want.URL)
+		}
+	}
+}
+
+func TestRandomPolicyOnlyPicksHealthy(t *testing.T) {
+	backends := []*Backend{testBackend(t, "http://a", 1)}
+	policy := &RandomPolicy{}
+	req := newRequest("10.0.0.1:1234")
+
+	for i := 0; i < 10; i++ {
+		if got := policy.Select(req, backends); got != backends[0] {
+			t.Fatalf("got %v, want %v", got.URL, backends[0].URL)
+		}
+	}
+}
+
+func TestLeastConnectionsPolicyPrefersIdleBackend(t *testing.T) {
+	busy := testBackend(t, "http://busy", 1)
+	idle := testBackend(t, "http://idle", 1)
+	busy.ActiveConnections = 5
+
+	policy := &LeastConnectionsPolicy{}
+	got := policy.Select(newRequest("10.0.0.1:1234"), []*Backend{busy, idle})
+	if got != idle {
+		t.Fatalf("got %v, want idle backend %v", got.URL, idle.URL)
+	}
+}
+
+func TestWeightedRoundRobinPolicyRespectsWeights(t *testing.T) {
+	heavy := testBackend(t, "http://heavy", 3)
+	light := testBackend(t, "http://light", 1)
+	backends := []*Backend{heavy, light}
+
+	policy := NewWeightedRoundRobinPolicy()
+	req := newRequest("10.0.0.1:1234")
+
+	counts := map[*Backend]int{}
+	for i := 0; i < 8; i++ {
+		counts[policy.Select(req, backends)]++
+	}
+
+	if counts[heavy] != 6 || counts[light] != 2 {
+		t.Fatalf("got heavy=%d light=%d, want heavy=6 light=2", counts[heavy], counts[light])
+	}
+}
+
+func TestIPHashPolicyIsStickyPerClient(t *testing.T) {
+	backends := []*Backend{
+		testBackend(t, "http://a", 1),
+		testBackend(t, "http://b", 1),
+		testBackend(t, "http://c", 1),
+	}
+	policy := &IPHashPolicy{}
+	req := newRequest("203.0.113.7:54321")
+
+	first := policy.Select(req, backends)
+	for i := 0; i < 5; i++ {
+		if got := policy.Select(req, backends); got != first {
+			t.Fatalf("pick %d: got %v, want sticky %v", i, got.URL, first.URL)
+		}
+	}
+}
+
+func TestGeoNearestPolicyFallsBackWithoutCoords(t *testing.T) {
+	backends := []*Backend{testBackend(t, "http://a", 1)}
+	fallback := &RandomPolicy{}
+	policy := NewGeoNearestPolicy(nil, fallback)
+
+	got := policy.Select(newRequest("10.0.0.1:1234"), backends)
+	if got != backends[0] {
+		t.Fatalf("got %v, want fallback pick %v", got.URL, backends[0].URL)
+	}
+}
+
+func TestSelectExcludesBackendThatWentUnhealthyMidRequest(t *testing.T) {
+	a := testBackend(t, "http://a", 1)
+	b := testBackend(t, "http://b", 1)
+
+	server := New(&RoundRobinPolicy{})
+	server.AddBackend(a)
+	server.AddBackend(b)
+
+	req := newRequest("10.0.0.1:1234")
+	first := server.Select(req)
+	if first == nil {
+		t.Fatal("expected a backend while both are healthy")
+	}
+
+	// The backend that was just selected goes down mid-request (e.g. the
+	// next health check cycle observes a failure).
+	first.SetAlive(false)
+
+	second := server.Select(req)
+	if second == nil {
+		t.Fatal("expected a backend after one went unhealthy")
+	}
+	if second == first {
+		t.Fatalf("Select returned the now-unhealthy backend %v", first.URL)
+	}
+}
+
+func TestHealthyBackendsDoesNotConsumeHalfOpenProbe(t *testing.T) {
+	a := testBackend(t, "http://a", 1)
+	a.Breaker = circuitbreaker.New(0.5, time.Minute, time.Millisecond)
+	for i := 0; i < 5; i++ {
+		a.Breaker.RecordFailure()
+	}
+	time.Sleep(2 * time.Millisecond)
+
+	server := New(&RoundRobinPolicy{})
+	server.AddBackend(a)
+	req := newRequest("10.0.0.1:1234")
+
+	// Scanning the candidate list, as HealthyBackends does on every
+	// request, must not itself admit the single half-open probe - only
+	// Select, once it has actually picked a.
+	for i := 0; i < 3; i++ {
+		healthy := server.HealthyBackends()
+		if len(healthy) != 1 {
+			t.Fatalf("HealthyBackends() = %d backends, want 1 candidate while past cooldown", len(healthy))
+		}
+	}
+	if got := a.Breaker.State(); got != circuitbreaker.Open {
+		t.Fatalf("state = %v, want still Open - HealthyBackends must not consume the probe", got)
+	}
+
+	if got := server.Select(req); got != a {
+		t.Fatalf("Select() = %v, want the half-open backend admitted", got)
+	}
+	if got := a.Breaker.State(); got != circuitbreaker.HalfOpen {
+		t.Fatalf("state = %v, want HalfOpen after Select actually routed to it", got)
+	}
+
+	// A second concurrent request must not get the same probe slot.
+	if got := server.Select(req); got != nil {
+		t.Fatalf("Select() = %v, want nil while a probe is already in flight", got)
+	}
+}
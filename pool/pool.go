@@ -0,0 +1,190 @@
+// Package pool holds the load balancer's backend pool and the pluggable
+// policies used to pick a backend for each incoming request.
+package pool
+
+import (
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"sync"
+	"sync/atomic"
+
+	"github.com/NghiaaPD/Load-Balancer-ResFes/circuitbreaker"
+	"github.com/NghiaaPD/Load-Balancer-ResFes/health"
+	"github.com/NghiaaPD/Load-Balancer-ResFes/vivaldi"
+)
+
+// Backend represents a single upstream server.
+type Backend struct {
+	URL          *url.URL
+	IP           string
+	Weight       int
+	Latitude     float64
+	Longitude    float64
+	HasCoords    bool
+	Coord        vivaldi.Coordinate
+	ReverseProxy *httputil.ReverseProxy
+
+	// Breaker, when set, can independently veto routing to this backend
+	// even while active health checks report it alive - see IsRoutable.
+	Breaker *circuitbreaker.Breaker
+
+	// Health, when set, is consulted by ServerPool.HealthCheck to decide
+	// this backend's alive state, replacing the old single-shot
+	// classification. It may also be fed passively from observed proxy
+	// responses - see IsRoutable and the health package.
+	Health *health.Checker
+
+	// ActiveConnections counts requests currently being proxied to this
+	// backend. It is updated with atomic ops from the handler wrapper in
+	// ServeHTTP; any reader, including policies and callers outside this
+	// package, must also use atomic.LoadInt64 rather than a plain read.
+	ActiveConnections int64
+
+	mu    sync.RWMutex
+	alive bool
+}
+
+// SetAlive updates the backend's health status. It has no effect once a
+// Health checker is configured - see IsAlive.
+func (b *Backend) SetAlive(alive bool) {
+	b.mu.Lock()
+	b.alive = alive
+	b.mu.Unlock()
+}
+
+// IsAlive reports whether the backend last passed a health check. When a
+// Health checker is configured, this defers to it directly rather than the
+// separately maintained alive flag: HealthCheck's active probes and a
+// passive observation fed in from the reverse proxy's response hook run
+// concurrently, and Checker is already safe for that, so routing all state
+// through it avoids two independent writers racing to set alive.
+func (b *Backend) IsAlive() bool {
+	if b.Health != nil {
+		return b.Health.Alive()
+	}
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.alive
+}
+
+// IsCandidate reports whether this backend could currently take a request:
+// it must have passed its last active health check, and its circuit breaker
+// (if any) must not be definitively Open. It never admits a half-open
+// probe - see IsRoutable - so it is safe to call on every backend while
+// building a candidate list without burning through each one's single
+// allotted probe before a policy has chosen between them.
+func (b *Backend) IsCandidate() bool {
+	if !b.IsAlive() {
+		return false
+	}
+	if b.Breaker == nil {
+		return true
+	}
+	return b.Breaker.Probeable()
+}
+
+// IsRoutable reports whether a request may actually be sent to this
+// backend right now, and - if its breaker is Open past Cooldown, or
+// HalfOpen - admits the single outstanding probe in doing so. Call this
+// only on the backend a policy has already selected, once per request;
+// calling it while scanning every candidate would let the scan itself
+// consume a backend's probe slot before any real traffic reached it.
+func (b *Backend) IsRoutable() bool {
+	if !b.IsAlive() {
+		return false
+	}
+	if b.Breaker == nil {
+		return true
+	}
+	return b.Breaker.Allow()
+}
+
+// ServeHTTP proxies the request to this backend, tracking the number of
+// requests currently in flight so LeastConnectionsPolicy has something to
+// read.
+func (b *Backend) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	atomic.AddInt64(&b.ActiveConnections, 1)
+	defer atomic.AddInt64(&b.ActiveConnections, -1)
+	b.ReverseProxy.ServeHTTP(w, r)
+}
+
+// Policy selects a backend from the currently healthy set for a request.
+// ServerPool only calls Select with a non-empty healthy slice; implementations
+// must be safe for concurrent use.
+type Policy interface {
+	Select(req *http.Request, healthy []*Backend) *Backend
+}
+
+// ServerPool owns the set of backends and the policy used to pick between
+// them.
+type ServerPool struct {
+	backends []*Backend
+	policy   Policy
+}
+
+// New creates a ServerPool that selects backends using policy.
+func New(policy Policy) *ServerPool {
+	return &ServerPool{policy: policy}
+}
+
+// AddBackend adds a backend to the pool.
+func (s *ServerPool) AddBackend(backend *Backend) {
+	s.backends = append(s.backends, backend)
+}
+
+// Backends returns every backend in the pool, healthy or not.
+func (s *ServerPool) Backends() []*Backend {
+	return s.backends
+}
+
+// HealthyBackends returns the subset of backends currently eligible to be
+// considered for a request: alive per the last health check, and not
+// vetoed by a tripped circuit breaker. This only peeks at each backend's
+// breaker - see IsCandidate - so calling it does not itself consume a
+// half-open probe slot.
+func (s *ServerPool) HealthyBackends() []*Backend {
+	var healthy []*Backend
+	for _, backend := range s.backends {
+		if backend.IsCandidate() {
+			healthy = append(healthy, backend)
+		}
+	}
+	return healthy
+}
+
+// HealthCheck runs one active probe per backend through its configured
+// Health checker and updates its alive state. Backends with no Health
+// checker configured are left untouched. onResult, when non-nil, is called
+// with the probe's raw Result and whether it flipped the backend's alive
+// state, after that state has been updated, so callers can report metrics
+// or logs, or feed latency samples into a policy, without duplicating the
+// probe.
+func (s *ServerPool) HealthCheck(onResult func(backend *Backend, result health.Result, transitioned bool)) {
+	for _, backend := range s.backends {
+		if backend.Health == nil {
+			continue
+		}
+		result, _, transitioned := backend.Health.Check()
+		if onResult != nil {
+			onResult(backend, result, transitioned)
+		}
+	}
+}
+
+// Select runs the pool's configured policy against the currently healthy
+// backends, returning nil when none are available. The chosen backend's
+// IsRoutable is checked once here, after the policy has picked it, so a
+// breaker's half-open probe is only ever consumed by the request actually
+// being routed to it - not by every request that merely considered it.
+func (s *ServerPool) Select(req *http.Request) *Backend {
+	healthy := s.HealthyBackends()
+	if len(healthy) == 0 {
+		return nil
+	}
+	backend := s.policy.Select(req, healthy)
+	if backend == nil || !backend.IsRoutable() {
+		return nil
+	}
+	return backend
+}
@@ -0,0 +1,87 @@
+// Package ratelimit throttles new connections per client using a
+// token-bucket keyed by an arbitrary string, typically the client's IP.
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// idleTTL is how long a key's bucket may go unseen before sweep reclaims
+// it. sweepInterval amortizes that reclaim so Allow stays O(1) on the
+// common path instead of scanning every key on every call.
+const (
+	idleTTL       = 10 * time.Minute
+	sweepInterval = time.Minute
+)
+
+type bucket struct {
+	tokens   float64
+	lastSeen time.Time
+}
+
+// Limiter enforces a maximum sustained rate of ratePerSecond per key, with
+// bursts up to burst tokens.
+type Limiter struct {
+	ratePerSecond float64
+	burst         float64
+
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	nextSweep time.Time
+}
+
+// New returns a Limiter allowing ratePerSecond requests per second per key,
+// with bursts up to ratePerSecond (one second's worth of tokens).
+func New(ratePerSecond float64) *Limiter {
+	return &Limiter{
+		ratePerSecond: ratePerSecond,
+		burst:         ratePerSecond,
+		buckets:       make(map[string]*bucket),
+	}
+}
+
+// Allow reports whether a request for key may proceed right now, consuming
+// a token if so.
+func (l *Limiter) Allow(key string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	l.sweep(now)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: l.burst, lastSeen: now}
+		l.buckets[key] = b
+	}
+
+	elapsed := now.Sub(b.lastSeen).Seconds()
+	b.lastSeen = now
+	b.tokens += elapsed * l.ratePerSecond
+	if b.tokens > l.burst {
+		b.tokens = l.burst
+	}
+
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// sweep evicts buckets that have gone unseen for longer than idleTTL, so a
+// long-running Limiter doesn't grow one entry per distinct key forever. It
+// is a no-op before nextSweep, amortizing the full-map scan to once per
+// sweepInterval. Callers must hold l.mu.
+func (l *Limiter) sweep(now time.Time) {
+	if now.Before(l.nextSweep) {
+		return
+	}
+	l.nextSweep = now.Add(sweepInterval)
+	for key, b := range l.buckets {
+		if now.Sub(b.lastSeen) > idleTTL {
+			delete(l.buckets, key)
+		}
+	}
+}
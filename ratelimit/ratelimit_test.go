@@ -0,0 +1,86 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLimiterAllowsUpToBurstThenBlocks(t *testing.T) {
+	l := New(2) // burst of 2 tokens
+
+	if !l.Allow("1.2.3.4") {
+		t.Fatal("expected first request to be allowed")
+	}
+	if !l.Allow("1.2.3.4") {
+		t.Fatal("expected second request within burst to be allowed")
+	}
+	if l.Allow("1.2.3.4") {
+		t.Fatal("expected third immediate request to be rejected")
+	}
+}
+
+func TestLimiterRefillsOverTime(t *testing.T) {
+	l := New(100) // fast refill so the test doesn't need to sleep long
+	if !l.Allow("1.2.3.4") {
+		t.Fatal("expected first request to be allowed")
+	}
+
+	// Drain the remaining burst.
+	for l.Allow("1.2.3.4") {
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	if !l.Allow("1.2.3.4") {
+		t.Fatal("expected a request to be allowed again after tokens refill")
+	}
+}
+
+func TestLimiterTracksClientsIndependently(t *testing.T) {
+	l := New(1)
+	if !l.Allow("a") {
+		t.Fatal("expected client a's first request to be allowed")
+	}
+	if !l.Allow("b") {
+		t.Fatal("expected client b's first request to be allowed even though a just used its token")
+	}
+}
+
+func TestSweepEvictsOnlyIdleBuckets(t *testing.T) {
+	l := New(1)
+	l.Allow("stale")
+	l.Allow("fresh")
+
+	now := time.Now()
+	l.buckets["stale"].lastSeen = now.Add(-idleTTL - time.Second)
+	l.nextSweep = time.Time{} // force the amortization window open
+
+	l.sweep(now)
+
+	if _, ok := l.buckets["stale"]; ok {
+		t.Fatal("expected sweep to evict a bucket idle past idleTTL")
+	}
+	if _, ok := l.buckets["fresh"]; !ok {
+		t.Fatal("expected sweep to keep a recently seen bucket")
+	}
+}
+
+func TestSweepIsAmortizedAcrossCalls(t *testing.T) {
+	l := New(1)
+	l.Allow("stale")
+
+	now := time.Now()
+	l.buckets["stale"].lastSeen = now.Add(-idleTTL - time.Second)
+	l.nextSweep = now.Add(time.Minute)
+
+	l.sweep(now)
+
+	if _, ok := l.buckets["stale"]; !ok {
+		t.Fatal("expected sweep to be a no-op before nextSweep")
+	}
+
+	l.sweep(l.nextSweep)
+
+	if _, ok := l.buckets["stale"]; ok {
+		t.Fatal("expected sweep to evict the idle bucket once nextSweep has passed")
+	}
+}
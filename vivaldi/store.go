@@ -0,0 +1,42 @@
+package vivaldi
+
+import (
+	"encoding/json"
+	"os"
+)
+
+// Store persists a node's own coordinate to disk so a restart resumes from
+// the last learned position instead of recalibrating from scratch. Only
+// the local node's coordinate needs persisting: backend coordinates in
+// this package are seeded deterministically and never move.
+type Store struct {
+	path string
+}
+
+// NewStore returns a Store backed by the file at path.
+func NewStore(path string) *Store {
+	return &Store{path: path}
+}
+
+// Load reads the persisted coordinate. ok is false if the file doesn't
+// exist or can't be parsed, in which case callers should fall back to
+// NewCoordinate and calibrate from scratch.
+func (s *Store) Load() (coord Coordinate, ok bool) {
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return Coordinate{}, false
+	}
+	if err := json.Unmarshal(data, &coord); err != nil {
+		return Coordinate{}, false
+	}
+	return coord, true
+}
+
+// Save writes coord to disk, overwriting whatever was there before.
+func (s *Store) Save(coord Coordinate) error {
+	data, err := json.Marshal(coord)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(s.path, data, 0o644)
+}
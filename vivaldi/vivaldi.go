@@ -0,0 +1,158 @@
+// Package vivaldi implements a Vivaldi-style network coordinate system:
+// each node keeps a low-dimensional coordinate that, once calibrated
+// against a handful of round-trip measurements, predicts latency to other
+// nodes without having to probe them directly.
+package vivaldi
+
+import (
+	"hash/fnv"
+	"math"
+	"math/rand"
+	"time"
+)
+
+const (
+	// initialError is the starting confidence for a fresh coordinate; a
+	// high value means "trust the first few samples a lot", per the usual
+	// Vivaldi bootstrap.
+	initialError = 1.5
+	minError     = 0.1
+	maxError     = 1.5
+
+	// errorWeight ("ce" in the Vivaldi paper) controls how quickly the
+	// local error estimate reacts to new samples.
+	errorWeight = 0.25
+	// adjustmentFactor ("cc" in the Vivaldi paper) caps how far a single
+	// sample can move the coordinate.
+	adjustmentFactor = 0.25
+)
+
+// Vector is a point in the coordinate space. Three dimensions is enough to
+// capture most of the structure of internet latency, per the original
+// Vivaldi paper.
+type Vector [3]float64
+
+// Coordinate is a node's position in the latency embedding, plus a height
+// term that models the "last mile" (access link) latency Euclidean space
+// alone underestimates, and an error estimate used to weigh updates.
+type Coordinate struct {
+	Vector Vector
+	Height float64
+	Error  float64
+}
+
+// NewCoordinate returns the coordinate a node starts at before any RTT
+// samples have been taken.
+func NewCoordinate() Coordinate {
+	return Coordinate{Error: initialError}
+}
+
+// SeedFromLatLon builds a starting coordinate from a geographic position,
+// using an equirectangular projection so nearby cities start out close in
+// the embedding. It's only a bootstrap: Update still corrects it against
+// measured RTTs.
+func SeedFromLatLon(lat, lon float64) Coordinate {
+	const kmPerDegreeLat = 110.574
+	x := lon * 111.320 * math.Cos(lat*math.Pi/180)
+	y := lat * kmPerDegreeLat
+	return Coordinate{Vector: Vector{x, y, 0}, Error: initialError}
+}
+
+// SeedFromString builds a deterministic starting coordinate from an
+// arbitrary label (e.g. a backend URL), for nodes with no known
+// geographic position. Using a hash instead of the process RNG keeps the
+// seed stable across restarts.
+func SeedFromString(label string) Coordinate {
+	h := fnv.New64a()
+	h.Write([]byte(label))
+	seed := h.Sum64()
+	rng := rand.New(rand.NewSource(int64(seed)))
+
+	const spread = 50 // kilometers
+	return Coordinate{
+		Vector: Vector{
+			(rng.Float64()*2 - 1) * spread,
+			(rng.Float64()*2 - 1) * spread,
+			(rng.Float64()*2 - 1) * spread,
+		},
+		Error: initialError,
+	}
+}
+
+// DistanceTo returns the predicted latency, in the same unit as the RTTs
+// Update was fed (this package treats it as seconds), between c and other.
+func (c Coordinate) DistanceTo(other Coordinate) float64 {
+	return magnitude(sub(c.Vector, other.Vector)) + c.Height + other.Height
+}
+
+// Update returns c moved toward (or away from) remote based on a freshly
+// measured round-trip time, following the standard Vivaldi update rule:
+// estimate the distance from the current coordinates, weigh the sample by
+// each side's relative confidence, and nudge c along the unit vector
+// toward/away from remote by a small, confidence-scaled step.
+func (c Coordinate) Update(remote Coordinate, rtt time.Duration) Coordinate {
+	rttSeconds := rtt.Seconds()
+	if rttSeconds <= 0 {
+		return c
+	}
+
+	est := c.DistanceTo(remote)
+	relativeError := math.Abs(rttSeconds-est) / rttSeconds
+
+	weight := c.Error / (c.Error + remote.Error)
+	newError := clamp(relativeError*errorWeight*weight+c.Error*(1-errorWeight*weight), minError, maxError)
+
+	direction, ok := unit(sub(c.Vector, remote.Vector))
+	if !ok {
+		direction = randomUnit()
+	}
+
+	step := adjustmentFactor * weight * (rttSeconds - est)
+	newVector := add(c.Vector, scale(direction, step))
+
+	return Coordinate{Vector: newVector, Height: c.Height, Error: newError}
+}
+
+func sub(a, b Vector) Vector {
+	return Vector{a[0] - b[0], a[1] - b[1], a[2] - b[2]}
+}
+
+func add(a, b Vector) Vector {
+	return Vector{a[0] + b[0], a[1] + b[1], a[2] + b[2]}
+}
+
+func scale(v Vector, factor float64) Vector {
+	return Vector{v[0] * factor, v[1] * factor, v[2] * factor}
+}
+
+func magnitude(v Vector) float64 {
+	return math.Sqrt(v[0]*v[0] + v[1]*v[1] + v[2]*v[2])
+}
+
+// unit returns v normalized to length 1. ok is false when v is (close to)
+// the zero vector, which happens when two coordinates coincide.
+func unit(v Vector) (Vector, bool) {
+	m := magnitude(v)
+	if m < 1e-9 {
+		return Vector{}, false
+	}
+	return scale(v, 1/m), true
+}
+
+func randomUnit() Vector {
+	v, ok := unit(Vector{rand.Float64()*2 - 1, rand.Float64()*2 - 1, rand.Float64()*2 - 1})
+	if !ok {
+		return Vector{1, 0, 0}
+	}
+	return v
+}
+
+func clamp(v, min, max float64) float64 {
+	if v < min {
+		return min
+	}
+	if v > max {
+		return max
+	}
+	return v
+}
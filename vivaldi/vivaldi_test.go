@@ -0,0 +1,76 @@
+package vivaldi
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// TestUpdateConvergesToSyntheticTopology places three landmarks on a known
+// line and checks that, after enough RTT samples generated straight from
+// their true distances, the local coordinate's predicted distances land in
+// the same order as the true ones.
+func TestUpdateConvergesToSyntheticTopology(t *testing.T) {
+	near := Coordinate{Vector: Vector{10, 0, 0}, Error: initialError}
+	mid := Coordinate{Vector: Vector{50, 0, 0}, Error: initialError}
+	far := Coordinate{Vector: Vector{200, 0, 0}, Error: initialError}
+
+	landmarks := []Coordinate{near, mid, far}
+	trueDistance := []float64{10, 50, 200}
+
+	// This package treats RTTs as seconds, so the synthetic samples use
+	// the same scale as the landmark coordinates: a "distance" of 10 means
+	// a 10-second measured round trip.
+	local := NewCoordinate()
+	for round := 0; round < 500; round++ {
+		for i, landmark := range landmarks {
+			rtt := time.Duration(trueDistance[i] * float64(time.Second))
+			local = local.Update(landmark, rtt)
+		}
+	}
+
+	gotNear := local.DistanceTo(near)
+	gotMid := local.DistanceTo(mid)
+	gotFar := local.DistanceTo(far)
+
+	if !(gotNear < gotMid && gotMid < gotFar) {
+		t.Fatalf("distances did not converge to the true order: near=%.2f mid=%.2f far=%.2f", gotNear, gotMid, gotFar)
+	}
+
+	// The predicted distance to the nearest landmark should end up
+	// reasonably close to the true 10s sample.
+	if diff := math.Abs(gotNear - trueDistance[0]); diff > 5 {
+		t.Fatalf("predicted distance to near landmark off by %.2f (got %.2f, want ~%.2f)", diff, gotNear, trueDistance[0])
+	}
+}
+
+func TestUpdateIgnoresNonPositiveRTT(t *testing.T) {
+	c := NewCoordinate()
+	got := c.Update(Coordinate{Vector: Vector{5, 0, 0}}, 0)
+	if got != c {
+		t.Fatalf("expected coordinate to be unchanged for a zero RTT, got %+v", got)
+	}
+}
+
+func TestUpdatePerturbsCoincidentCoordinates(t *testing.T) {
+	c := NewCoordinate()
+	remote := NewCoordinate() // same position as c
+
+	got := c.Update(remote, 20*time.Millisecond)
+	if got.Vector == c.Vector {
+		t.Fatal("expected Update to move away from a coincident coordinate via random perturbation")
+	}
+}
+
+func TestSeedFromStringIsDeterministic(t *testing.T) {
+	a := SeedFromString("http://backend-1:9000")
+	b := SeedFromString("http://backend-1:9000")
+	if a.Vector != b.Vector {
+		t.Fatalf("expected identical seeds for the same label, got %+v and %+v", a.Vector, b.Vector)
+	}
+
+	c := SeedFromString("http://backend-2:9000")
+	if a.Vector == c.Vector {
+		t.Fatal("expected different labels to seed different coordinates")
+	}
+}